@@ -0,0 +1,104 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+)
+
+// Cache stores parsed Config values keyed by the fingerprint of the config
+// they were parsed from, letting long-running services skip re-parsing
+// configuration that has not changed.
+type Cache interface {
+	Get(hash string) (*Config, bool)
+	Put(hash string, c *Config)
+}
+
+// Fingerprint computes a stable hash over fname and the transitive set of
+// files it includes via #include, #include_dir and #include_optional,
+// using the same algorithm as golang.org/x/mod/sumdb/dirhash.Hash1: every
+// file contributes a line "sha256hex(content)  path\n", the lines are
+// ordered by sorting the file paths, and the concatenation is hashed again
+// and prefixed with "h1:".
+func Fingerprint(fname string) (string, error) {
+	return FingerprintFS(OS, fname)
+}
+
+// FingerprintFS is like Fingerprint but reads through fs.
+func FingerprintFS(fs FS, fname string) (string, error) {
+	list := &fileList{}
+	if err := list.pushFile(fs, fname); err != nil {
+		return "", err
+	}
+
+	// A throwaway Config drives the #include* directive resolution; its
+	// parsed sections are discarded once the transitive file set is known.
+	capture := map[string][]byte{}
+	if _, err := _read(fs, NewDefault(), list, capture); err != nil {
+		return "", err
+	}
+
+	return dirhash1(capture), nil
+}
+
+// dirhash1 implements golang.org/x/mod/sumdb/dirhash.Hash1 over an
+// in-memory file set.
+func dirhash1(files map[string][]byte) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		sum := sha256.Sum256(files[path])
+		fmt.Fprintf(h, "%x  %s\n", sum, path)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ReadCached reads a configuration file, consulting cache first and
+// populating it on a miss. It uses the default values, the same as
+// ReadDefault. The fingerprint used to key the cache is returned alongside
+// the Config so callers can log it, compare it across a fleet, or key
+// their own bookkeeping by it without Config having to remember it.
+func ReadCached(fname string, cache Cache) (*Config, string, error) {
+	return ReadCachedFS(OS, fname, cache)
+}
+
+// ReadCachedFS is like ReadCached but reads through fs.
+func ReadCachedFS(fs FS, fname string, cache Cache) (*Config, string, error) {
+	hash, err := FingerprintFS(fs, fname)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if c, ok := cache.Get(hash); ok {
+		return c, hash, nil
+	}
+
+	c, err := ReadDefaultFS(fs, fname)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cache.Put(hash, c)
+	return c, hash, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprintFSIsStableAndContentAddressed(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/app.conf", []byte("[main]\nfoo = bar\n"))
+
+	h1, err := FingerprintFS(fs, "/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(h1, "h1:") {
+		t.Fatalf("fingerprint missing h1: prefix: %q", h1)
+	}
+
+	h2, err := FingerprintFS(fs, "/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 != h2 {
+		t.Fatalf("fingerprint not stable across calls: %q vs %q", h1, h2)
+	}
+
+	fs.Add("/app.conf", []byte("[main]\nfoo = baz\n"))
+	h3, err := FingerprintFS(fs, "/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h3 == h1 {
+		t.Fatalf("fingerprint did not change with content")
+	}
+}
+
+func TestFingerprintFSCoversIncludes(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/app.conf", []byte("[main]\nfoo = bar\n#include other.conf\n"))
+	fs.Add("/other.conf", []byte("[main]\nbaz = qux\n"))
+
+	h1, err := FingerprintFS(fs, "/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Add("/other.conf", []byte("[main]\nbaz = changed\n"))
+	h2, err := FingerprintFS(fs, "/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h1 == h2 {
+		t.Fatalf("fingerprint did not change when an included file changed")
+	}
+}
+
+type memCache struct {
+	m map[string]*Config
+}
+
+func newMemCache() *memCache {
+	return &memCache{m: make(map[string]*Config)}
+}
+
+func (c *memCache) Get(hash string) (*Config, bool) {
+	cfg, ok := c.m[hash]
+	return cfg, ok
+}
+
+func (c *memCache) Put(hash string, cfg *Config) {
+	c.m[hash] = cfg
+}
+
+func TestReadCachedFSHitsOnSecondRead(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/app.conf", []byte("[main]\nfoo = bar\n"))
+	cache := newMemCache()
+
+	c1, h1, err := ReadCachedFS(fs, "/app.conf", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c2, h2, err := ReadCachedFS(fs, "/app.conf", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h1 != h2 {
+		t.Fatalf("fingerprint changed between reads of the same content: %q vs %q", h1, h2)
+	}
+	if c1 != c2 {
+		t.Fatalf("expected a cache hit to return the same *Config, got a new one")
+	}
+}
+
+func TestReadCachedFSMissesOnChangedContent(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/app.conf", []byte("[main]\nfoo = bar\n"))
+	cache := newMemCache()
+
+	c1, h1, err := ReadCachedFS(fs, "/app.conf", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Add("/app.conf", []byte("[main]\nfoo = changed\n"))
+	c2, h2, err := ReadCachedFS(fs, "/app.conf", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h1 == h2 {
+		t.Fatalf("expected fingerprint to change with content")
+	}
+	if c1 == c2 {
+		t.Fatalf("expected a fresh *Config on a cache miss")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// reExpandRef matches $$ (an escaped, literal $) and ${ref} placeholders.
+var reExpandRef = regexp.MustCompile(`\$\$|\$\{([^}]*)\}`)
+
+// Expand rewrites every option value in place, replacing `${VAR}`
+// references with mapping(VAR) and `${section.option}` references with the
+// value of that option, recursively. `$$` is an escape for a literal `$`.
+// It returns an error if a `${section.option}` reference forms a cycle.
+func (c *Config) Expand(mapping func(string) string) error {
+	type update struct{ section, option, value string }
+	var updates []update
+
+	for _, section := range c.Sections() {
+		for _, option := range c.SectionOptions(section) {
+			value, err := c.RawString(section, option)
+			if err != nil {
+				return err
+			}
+
+			expanded, err := c.expandValue(value, mapping, make(map[string]bool))
+			if err != nil {
+				return err
+			}
+
+			if expanded != value {
+				updates = append(updates, update{section, option, expanded})
+			}
+		}
+	}
+
+	// Values are written back only once all of them are expanded, so every
+	// ${section.option} reference resolves against the value as parsed.
+	for _, u := range updates {
+		c.AddOption(u.section, u.option, u.value)
+	}
+	return nil
+}
+
+// ExpandEnv is a convenience wrapper over Expand that resolves `${VAR}`
+// references against the process environment via os.Getenv.
+func (c *Config) ExpandEnv() error {
+	return c.Expand(os.Getenv)
+}
+
+// expandValue replaces every reference in value. visiting tracks the
+// section.option keys currently being resolved, to detect cycles such as
+// "a" referencing "${b}" while "b" references "${a}".
+func (c *Config) expandValue(value string, mapping func(string) string, visiting map[string]bool) (string, error) {
+	var expandErr error
+
+	expanded := reExpandRef.ReplaceAllStringFunc(value, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		if match == "$$" {
+			return "$"
+		}
+
+		key := match[2 : len(match)-1]
+		resolved, err := c.resolveRef(key, mapping, visiting)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		return resolved
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+
+	return expanded, nil
+}
+
+// resolveRef resolves a single ${key} reference. Keys of the form
+// "section.option" are resolved against the config itself, recursively
+// expanding any references they contain in turn; every other key is
+// resolved through mapping.
+func (c *Config) resolveRef(key string, mapping func(string) string, visiting map[string]bool) (string, error) {
+	section, option, ok := splitSectionOption(key)
+	if !ok {
+		return mapping(key), nil
+	}
+
+	if visiting[key] {
+		return "", fmt.Errorf("config: reference cycle detected at %q", key)
+	}
+
+	raw, err := c.RawString(section, option)
+	if err != nil {
+		// Not a known option: fall back to mapping, the same as a plain
+		// ${VAR} reference.
+		return mapping(key), nil
+	}
+
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	return c.expandValue(raw, mapping, visiting)
+}
+
+// splitSectionOption splits a "section.option" reference key. ok is false
+// for keys without a dot, i.e. plain variable names.
+func splitSectionOption(key string) (section, option string, ok bool) {
+	i := strings.IndexByte(key, '.')
+	if i < 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
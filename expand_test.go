@@ -0,0 +1,109 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandResolvesSectionOptionAndMappingReferences(t *testing.T) {
+	c := NewDefault()
+	c.AddSection("main")
+	c.AddOption("main", "host", "example.com")
+	c.AddOption("main", "url", "https://${main.host}/${path}")
+
+	mapping := func(key string) string {
+		if key == "path" {
+			return "status"
+		}
+		return ""
+	}
+
+	if err := c.Expand(mapping); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.RawString("main", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/status"; got != want {
+		t.Fatalf("url = %q, want %q", got, want)
+	}
+}
+
+func TestExpandEscapesDoubleDollar(t *testing.T) {
+	c := NewDefault()
+	c.AddSection("main")
+	c.AddOption("main", "price", "costs $$5")
+
+	if err := c.Expand(func(string) string { return "" }); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := c.RawString("main", "price")
+	if want := "costs $5"; got != want {
+		t.Fatalf("price = %q, want %q", got, want)
+	}
+}
+
+func TestExpandDetectsDirectCycle(t *testing.T) {
+	c := NewDefault()
+	c.AddSection("a")
+	c.AddOption("a", "x", "${a.y}")
+	c.AddOption("a", "y", "${a.x}")
+
+	err := c.Expand(func(string) string { return "" })
+	if err == nil {
+		t.Fatal("expected a cycle error")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExpandDetectsCycleAcrossSections(t *testing.T) {
+	c := NewDefault()
+	c.AddSection("a")
+	c.AddSection("b")
+	c.AddOption("a", "x", "${b.y}")
+	c.AddOption("b", "y", "${a.x}")
+
+	err := c.Expand(func(string) string { return "" })
+	if err == nil {
+		t.Fatal("expected a cycle error")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExpandEnvUsesProcessEnvironment(t *testing.T) {
+	t.Setenv("CONFIG_EXPAND_TEST_VAR", "injected")
+
+	c := NewDefault()
+	c.AddSection("main")
+	c.AddOption("main", "value", "${CONFIG_EXPAND_TEST_VAR}")
+
+	if err := c.ExpandEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := c.RawString("main", "value")
+	if want := "injected"; got != want {
+		t.Fatalf("value = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FS abstracts the filesystem operations needed to read a configuration
+// file and resolve its includes. It is implemented by OS for the real
+// filesystem and by MemFS for in-memory configurations (embedded assets,
+// archives, tests, ...).
+type FS interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+	// Glob returns the names of all files matching pattern.
+	Glob(pattern string) ([]string, error)
+	// Abs returns an absolute representation of path.
+	Abs(path string) (string, error)
+	// ReadDir returns the sorted, absolute paths of the regular files
+	// directly inside dir. Sub-directories are omitted.
+	ReadDir(dir string) ([]string, error)
+	// Walk calls fn with the path of every regular file found by
+	// recursively descending root, in lexicographical order.
+	Walk(root string, fn func(path string) error) error
+}
+
+// OS is the FS backed by the local filesystem via the os and path/filepath
+// packages. It is the FS used by Read and ReadDefault.
+var OS FS = osFS{}
+
+// osFS implements FS on top of the os and path/filepath packages.
+type osFS struct{}
+
+func (osFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+func (osFS) Abs(path string) (string, error) {
+	return filepath.Abs(path)
+}
+
+func (osFS) ReadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return names, nil
+}
+
+// Walk mirrors the way filepath.Glob tolerates a non-existent directory: if
+// root itself does not exist there is nothing to walk, so Walk returns no
+// error and visits nothing, rather than surfacing the lstat failure.
+func (osFS) Walk(root string, fn func(path string) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			return fn(path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOSWalkMissingRootIsNotAnError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "conf.d")
+
+	var visited []string
+	if err := OS.Walk(missing, func(path string) error {
+		visited = append(visited, path)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk on a missing root returned an error: %v", err)
+	}
+	if len(visited) != 0 {
+		t.Fatalf("expected no files visited, got %v", visited)
+	}
+}
+
+func TestPushOptionalFileMissingRecursiveGlobDir(t *testing.T) {
+	list := &fileList{}
+	err := list.pushOptionalFile(OS, filepath.Join(t.TempDir(), "conf.d", "**", "*.conf"))
+	if err != nil {
+		t.Fatalf("#include_optional of a missing conf.d/** should be silently ignored, got: %v", err)
+	}
+	if len(*list) != 0 {
+		t.Fatalf("expected no candidates pushed, got %v", *list)
+	}
+}
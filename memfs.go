@@ -0,0 +1,107 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MemFS is an in-memory FS, similar in spirit to spf13/afero's MemMapFs.
+// It lets callers load configuration from embedded assets or archives, and
+// lets tests exercise Read/ReadDefault without touching disk.
+type MemFS struct {
+	files map[string][]byte
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string][]byte)}
+}
+
+// Add registers content under name, overwriting any previous content.
+func (fs *MemFS) Add(name string, content []byte) {
+	fs.files[name] = content
+}
+
+// Open implements FS.
+func (fs *MemFS) Open(name string) (io.ReadCloser, error) {
+	content, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Glob implements FS.
+func (fs *MemFS) Glob(pattern string) ([]string, error) {
+	var matches []string
+	for name := range fs.files {
+		ok, err := filepath.Match(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Abs implements FS. Paths are rooted at "/" since MemFS has no notion of a
+// current working directory.
+func (fs *MemFS) Abs(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	return filepath.Join("/", path), nil
+}
+
+// ReadDir implements FS. MemFS has no real directories, so any registered
+// file whose parent equals dir is considered to be directly inside it.
+func (fs *MemFS) ReadDir(dir string) ([]string, error) {
+	var names []string
+	for name := range fs.files {
+		if filepath.Dir(name) == dir {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Walk implements FS. It visits every registered file whose path is rooted
+// at or under root, in lexicographical order.
+func (fs *MemFS) Walk(root string, walkFn func(path string) error) error {
+	var names []string
+	for name := range fs.files {
+		if name == root || strings.HasPrefix(name, root+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := walkFn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
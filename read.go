@@ -16,10 +16,13 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -27,78 +30,182 @@ import (
 var (
 	// The regexp test if the path contains globs * and/or ?
 	reGlob = regexp.MustCompile(`[\*\?]`)
+	// The regexp tests if the path contains a recursive glob **
+	reRecursiveGlob = regexp.MustCompile(`\*\*`)
 	// The regexp is for matching include file directive
 	reIncludeFile = regexp.MustCompile(`^#include\s+(.+?)\s*$`)
+	// The regexp is for matching include directory directive
+	reIncludeDir = regexp.MustCompile(`^#include_dir\s+(.+?)\s*$`)
+	// The regexp is for matching optional include file directive
+	reIncludeOptional = regexp.MustCompile(`^#include_optional\s+(.+?)\s*$`)
 )
 
 // configFile identifies a file which should be read.
 type configFile struct {
-	Path string
-	Read bool
+	Path     string
+	Read     bool
+	Optional bool
 }
 
 // fileList is the list of files to read.
 type fileList []*configFile
 
-// pushFile converts the path into the absolute path and pushes the file
-// into the list if it does not contain the same absolute path already.
-// All relative paths are relative to the main file which is the first
-// file in the list.
-func (list *fileList) pushFile(path string) error {
-	var (
-		absPath string
-		err     error
-	)
-
-	// Convert the path into the absolute path
-	if !filepath.IsAbs(path) {
-		// Make the path relative to the main file
-		var relPath string
-		if len(*list) > 0 {
-			// Join the relative path with the main file path
-			relPath = filepath.Join(filepath.Dir((*list)[0].Path), path)
-		} else {
-			relPath = path
-		}
-
-		if absPath, err = filepath.Abs(relPath); err != nil {
-			return err
-		}
-	} else {
-		absPath = path
+// resolveAbs converts path into an absolute path. All relative paths are
+// relative to the main file which is the first file in the list.
+func (list *fileList) resolveAbs(fs FS, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
 	}
 
-	// Make the list of file candidates to include
-	var candidates []string
-	if reGlob.MatchString(absPath) {
-		candidates, err = filepath.Glob(absPath)
-		if err != nil {
-			return err
-		}
-	} else {
-		candidates = []string{absPath}
+	// Make the path relative to the main file
+	relPath := path
+	if len(*list) > 0 {
+		relPath = filepath.Join(filepath.Dir((*list)[0].Path), path)
 	}
 
+	return fs.Abs(relPath)
+}
+
+// pushCandidates pushes every candidate path into the list, skipping the
+// ones that are already present. Candidates marked optional are silently
+// skipped by _readFile if the underlying file does not exist.
+func (list *fileList) pushCandidates(candidates []string, optional bool) {
 	for _, candidate := range candidates {
-		// Test the file with the absolute path exists in the list
+		already := false
 		for _, file := range *list {
 			if file.Path == candidate {
-				return nil
+				already = true
+				break
 			}
 		}
+		if already {
+			continue
+		}
 
-		// Push the new file to the list
 		*list = append(*list, &configFile{
-			Path: candidate,
-			Read: false,
+			Path:     candidate,
+			Read:     false,
+			Optional: optional,
 		})
 	}
+}
+
+// push converts path into the absolute path and pushes the matching
+// file(s) into the list, skipping the ones that are already present. All
+// relative paths are relative to the main file which is the first file in
+// the list. path may contain the globs * and ? or a recursive ** that
+// matches any number of directories. A missing directory behind a glob
+// yields no candidates rather than an error, the same as filepath.Glob
+// already does for a plain * pattern. When optional is true, candidates
+// are pushed as configFile.Optional so _readFile tolerates a missing file
+// at read time too.
+func (list *fileList) push(fs FS, path string, optional bool) error {
+	absPath, err := list.resolveAbs(fs, path)
+	if err != nil {
+		return err
+	}
+
+	// Make the list of file candidates to include
+	var candidates []string
+	switch {
+	case reRecursiveGlob.MatchString(absPath):
+		candidates, err = walkGlob(fs, absPath)
+	case reGlob.MatchString(absPath):
+		candidates, err = fs.Glob(absPath)
+	default:
+		candidates = []string{absPath}
+	}
+	if err != nil {
+		return err
+	}
+
+	list.pushCandidates(candidates, optional)
+	return nil
+}
+
+// pushFile is push with optional set to false.
+func (list *fileList) pushFile(fs FS, path string) error {
+	return list.push(fs, path, false)
+}
+
+// pushOptionalFile behaves like pushFile, except that a literal path which
+// does not exist is silently ignored instead of aborting the parse. A glob
+// which matches nothing pushes nothing, the same as for pushFile. Genuine
+// I/O errors (e.g. a directory
+// listing that fails for reasons other than a missing path) still abort.
+func (list *fileList) pushOptionalFile(fs FS, path string) error {
+	return list.push(fs, path, true)
+}
+
+// pushDir pushes every regular file directly inside dir into the list,
+// sorted lexicographically, skipping files that are already present.
+func (list *fileList) pushDir(fs FS, dir string) error {
+	absDir, err := list.resolveAbs(fs, dir)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := fs.ReadDir(absDir)
+	if err != nil {
+		return err
+	}
 
+	list.pushCandidates(candidates, false)
 	return nil
 }
 
-// _read reads file list
-func _read(c *Config, list *fileList) (*Config, error) {
+// walkGlob expands a pattern containing a recursive ** component by
+// walking the directory tree rooted just before the ** and filtering the
+// visited files by the pattern remaining after it, the way shells expand
+// "dir/**/*.conf" style globs. ** matches any number of intervening
+// directories, including zero, so a path matches as long as its last
+// len(restParts) components match restParts component-by-component;
+// whatever precedes that suffix is what ** absorbed.
+func walkGlob(fs FS, pattern string) ([]string, error) {
+	parts := strings.SplitN(pattern, "**", 2)
+	root := strings.TrimSuffix(parts[0], string(filepath.Separator))
+	rest := strings.TrimPrefix(parts[1], string(filepath.Separator))
+	if rest == "" {
+		rest = "*"
+	}
+	restParts := strings.Split(rest, string(filepath.Separator))
+
+	var matches []string
+	err := fs.Walk(root, func(path string) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		relParts := strings.Split(rel, string(filepath.Separator))
+		if len(relParts) < len(restParts) {
+			return nil
+		}
+		suffix := relParts[len(relParts)-len(restParts):]
+
+		for i, pat := range restParts {
+			matched, err := filepath.Match(pat, suffix[i])
+			if err != nil {
+				return err
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		matches = append(matches, path)
+		return nil
+	})
+
+	sort.Strings(matches)
+	return matches, err
+}
+
+// _read reads file list. When capture is non-nil, the raw content of every
+// file read is recorded into it, keyed by absolute path, for callers such
+// as Fingerprint that need the transitive include set rather than a parsed
+// Config.
+func _read(fs FS, c *Config, list *fileList, capture map[string][]byte) (*Config, error) {
 	// Pass through the list untill all files are read
 	for {
 		hasUnread := false
@@ -106,7 +213,7 @@ func _read(c *Config, list *fileList) (*Config, error) {
 		// Go through the list and read files
 		for _, file := range *list {
 			if !file.Read {
-				if err := _readFile(file.Path, c, list); err != nil {
+				if err := _readFile(fs, file, c, list, capture); err != nil {
 					return nil, err
 				}
 
@@ -126,21 +233,33 @@ func _read(c *Config, list *fileList) (*Config, error) {
 
 // _readFile is the base to read a file and get the configuration representation.
 // That representation can be queried with GetString, etc.
-func _readFile(fname string, c *Config, list *fileList) error {
-	file, err := os.Open(fname)
+func _readFile(fs FS, cfgFile *configFile, c *Config, list *fileList, capture map[string][]byte) error {
+	f, err := fs.Open(cfgFile.Path)
 	if err != nil {
+		if cfgFile.Optional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
 		return err
 	}
 
 	// Defer closing the file so we can be sure the underlying file handle
 	// will be closed in any case.
-	defer file.Close()
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	if capture != nil {
+		capture[cfgFile.Path] = data
+	}
 
-	if err = c.read(bufio.NewReader(file), list); err != nil {
+	if err = c.read(fs, bufio.NewReader(bytes.NewReader(data)), list); err != nil {
 		return err
 	}
 
-	if err = file.Close(); err != nil {
+	if err = f.Close(); err != nil {
 		return err
 	}
 
@@ -150,24 +269,42 @@ func _readFile(fname string, c *Config, list *fileList) error {
 // Read reads a configuration file and returns its representation.
 // All arguments, except `fname`, are related to `New()`
 func Read(fname string, comment, separator string, preSpace, postSpace bool) (*Config, error) {
-	list := &fileList{}
-	list.pushFile(fname)
-
-	return _read(New(comment, separator, preSpace, postSpace), list)
+	return ReadFS(OS, fname, comment, separator, preSpace, postSpace)
 }
 
 // ReadDefault reads a configuration file and returns its representation.
 // It uses values by default.
 func ReadDefault(fname string) (*Config, error) {
+	return ReadDefaultFS(OS, fname)
+}
+
+// ReadFS reads a configuration file through fs and returns its
+// representation. All arguments, except `fs` and `fname`, are related to
+// `New()`. It lets callers load configs from embedded assets, archives, or
+// any other FS implementation instead of the local filesystem.
+func ReadFS(fs FS, fname string, comment, separator string, preSpace, postSpace bool) (*Config, error) {
 	list := &fileList{}
-	list.pushFile(fname)
+	if err := list.pushFile(fs, fname); err != nil {
+		return nil, err
+	}
 
-	return _read(NewDefault(), list)
+	return _read(fs, New(comment, separator, preSpace, postSpace), list, nil)
+}
+
+// ReadDefaultFS reads a configuration file through fs and returns its
+// representation. It uses values by default.
+func ReadDefaultFS(fs FS, fname string) (*Config, error) {
+	list := &fileList{}
+	if err := list.pushFile(fs, fname); err != nil {
+		return nil, err
+	}
+
+	return _read(fs, NewDefault(), list, nil)
 }
 
 // * * *
 
-func (c *Config) read(buf *bufio.Reader, list *fileList) (err error) {
+func (c *Config) read(fs FS, buf *bufio.Reader, list *fileList) (err error) {
 	var section, option string
 	var scanner = bufio.NewScanner(buf)
 	for scanner.Scan() {
@@ -182,9 +319,26 @@ func (c *Config) read(buf *bufio.Reader, list *fileList) (err error) {
 		// Comments starting with ;
 		case l[0] == '#':
 			// Test for possible directives
-			if matches := reIncludeFile.FindStringSubmatch(l); matches != nil {
-				list.pushFile(matches[1])
-			} else {
+			switch {
+			case reIncludeFile.MatchString(l):
+				matches := reIncludeFile.FindStringSubmatch(l)
+				if err := list.pushFile(fs, matches[1]); err != nil {
+					return err
+				}
+
+			case reIncludeDir.MatchString(l):
+				matches := reIncludeDir.FindStringSubmatch(l)
+				if err := list.pushDir(fs, matches[1]); err != nil {
+					return err
+				}
+
+			case reIncludeOptional.MatchString(l):
+				matches := reIncludeOptional.FindStringSubmatch(l)
+				if err := list.pushOptionalFile(fs, matches[1]); err != nil {
+					return err
+				}
+
+			default:
 				continue
 			}
 
@@ -0,0 +1,101 @@
+// Copyright 2009  The "config" Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "testing"
+
+func TestPushDirSortsAndSkipsSubdirectories(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/conf.d/20-b.conf", []byte("b"))
+	fs.Add("/conf.d/10-a.conf", []byte("a"))
+	fs.Add("/conf.d/nested/ignored.conf", []byte("c"))
+
+	list := &fileList{}
+	if err := list.pushDir(fs, "/conf.d"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*list) != 2 {
+		t.Fatalf("want 2 files, got %d: %v", len(*list), *list)
+	}
+	if (*list)[0].Path != "/conf.d/10-a.conf" || (*list)[1].Path != "/conf.d/20-b.conf" {
+		t.Fatalf("unexpected order: %q, %q", (*list)[0].Path, (*list)[1].Path)
+	}
+}
+
+func TestPushFileRecursiveGlob(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/conf.d/a/one.conf", []byte("1"))
+	fs.Add("/conf.d/b/two.conf", []byte("2"))
+	fs.Add("/conf.d/b/ignore.txt", []byte("x"))
+
+	list := &fileList{}
+	if err := list.pushFile(fs, "/conf.d/**/*.conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*list) != 2 {
+		t.Fatalf("want 2 files, got %d: %v", len(*list), *list)
+	}
+	for _, file := range *list {
+		if file.Path != "/conf.d/a/one.conf" && file.Path != "/conf.d/b/two.conf" {
+			t.Fatalf("unexpected match: %q", file.Path)
+		}
+	}
+}
+
+func TestPushFileRecursiveGlobWithMultiSegmentRemainder(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/conf.d/sub/one.conf", []byte("zero intervening dirs"))
+	fs.Add("/conf.d/a/sub/two.conf", []byte("one intervening dir"))
+	fs.Add("/conf.d/a/b/sub/three.conf", []byte("two intervening dirs"))
+	fs.Add("/conf.d/a/other/four.conf", []byte("does not end in sub/*.conf"))
+
+	list := &fileList{}
+	if err := list.pushFile(fs, "/conf.d/**/sub/*.conf"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"/conf.d/sub/one.conf":       true,
+		"/conf.d/a/sub/two.conf":     true,
+		"/conf.d/a/b/sub/three.conf": true,
+	}
+	if len(*list) != len(want) {
+		t.Fatalf("want %d files, got %d: %v", len(want), len(*list), *list)
+	}
+	for _, file := range *list {
+		if !want[file.Path] {
+			t.Fatalf("unexpected match: %q", file.Path)
+		}
+	}
+}
+
+func TestPushFileDeduplicatesRepeatedPath(t *testing.T) {
+	fs := NewMemFS()
+	fs.Add("/app.conf", []byte("main"))
+	fs.Add("/conf.d/app.conf", []byte("dup"))
+
+	list := &fileList{}
+	if err := list.pushFile(fs, "/app.conf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.pushFile(fs, "/app.conf"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*list) != 1 {
+		t.Fatalf("want the second push to be a no-op, got %d entries: %v", len(*list), *list)
+	}
+}